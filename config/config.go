@@ -0,0 +1,34 @@
+package config
+
+import "sync/atomic"
+
+// Configuration is the root of the wings instance configuration.
+type Configuration struct {
+	System SystemConfiguration `yaml:"system"`
+}
+
+// SystemConfiguration holds instance-wide behavior toggles that are not specific to any
+// one server.
+type SystemConfiguration struct {
+	// DisabledArchiveFormats lists archive formats, by their canonical names (e.g. "7z",
+	// "tar.gz"), that administrators have chosen to disable instance-wide for both
+	// compression and decompression. Entries are compared exactly; aliases that the
+	// compress endpoint's "format" field accepts, such as "tgz", do not match.
+	DisabledArchiveFormats []string `yaml:"disabled_archive_formats"`
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(&Configuration{})
+}
+
+// Get returns the currently loaded instance configuration.
+func Get() *Configuration {
+	return current.Load().(*Configuration)
+}
+
+// Set replaces the currently loaded instance configuration.
+func Set(c *Configuration) {
+	current.Store(c)
+}
@@ -1,20 +1,68 @@
 package router
 
 import (
-	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/server"
 	"golang.org/x/sync/errgroup"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
-	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
+// isArchiveFormatDisabled checks the instance configuration for a list of archive
+// formats that administrators have chosen to disable, e.g. formats they don't want
+// available to untrusted uploads.
+func isArchiveFormatDisabled(format server.ArchiveFormat) bool {
+	for _, disabled := range config.Get().System.DisabledArchiveFormats {
+		if server.ArchiveFormat(disabled) == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wantsAsync reports whether the client requested that a potentially slow file
+// operation be enqueued and tracked via the operations subsystem rather than blocking
+// the request until it finishes.
+func wantsAsync(c *gin.Context) bool {
+	return c.Query("async") == "true"
+}
+
+// runOrEnqueue either runs fn to completion and handles its error the way every other
+// endpoint in this file does, or — if the client asked for an async operation — enqueues
+// it and responds with the operation's ID so progress can be followed over SSE.
+func runOrEnqueue(c *gin.Context, s *server.Server, fn func(ctx context.Context, report server.ProgressReporter) error) {
+	if wantsAsync(c) {
+		op := s.Operations().Enqueue(context.Background(), fn)
+		c.JSON(http.StatusAccepted, gin.H{"operation": op.ID})
+		return
+	}
+
+	// Use a background context here, not the request's: a client disconnecting midway
+	// through a synchronous bulk delete/rename should not silently abort it partway
+	// through with no record of what was and wasn't applied. Async operations already
+	// get their own independently-cancellable context via Enqueue above.
+	if err := fn(context.Background(), func(processed, total int64, current string) {}); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		TrackedServerError(err, s).AbortWithServerError(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // Returns the contents of a file on the server.
 func getServerFileContents(c *gin.Context) {
 	s := GetServer(c.Param("server"))
@@ -55,7 +103,6 @@ func getServerFileContents(c *gin.Context) {
 	defer f.Close()
 
 	c.Header("X-Mime-Type", st.Mimetype)
-	c.Header("Content-Length", strconv.Itoa(int(st.Info.Size())))
 
 	// If a download parameter is included in the URL go ahead and attach the necessary headers
 	// so that the file can be downloaded.
@@ -64,7 +111,18 @@ func getServerFileContents(c *gin.Context) {
 		c.Header("Content-Type", "application/octet-stream")
 	}
 
-	bufio.NewReader(f).WriteTo(c.Writer)
+	// Set a stable ETag from the file size and modification time so that clients can
+	// issue conditional requests, and let http.ServeContent take care of Range,
+	// If-Modified-Since, and If-None-Match handling (including 206 partial responses).
+	c.Header("ETag", etagFor(st.Info))
+	http.ServeContent(c.Writer, c.Request, st.Info.Name(), st.Info.ModTime(), f)
+}
+
+// etagFor computes a stable entity tag for a file based on its size and modification
+// time. This avoids hashing file contents while still changing whenever the underlying
+// file is replaced.
+func etagFor(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
 }
 
 // Returns the contents of a directory for a server.
@@ -118,39 +176,40 @@ func putServerRenameFiles(c *gin.Context) {
 		return
 	}
 
-	g, ctx := errgroup.WithContext(context.Background())
-
-	// Loop over the array of files passed in and perform the move or rename action against each.
-	for _, p := range data.Files {
-		pf := path.Join(data.Root, p.From)
-		pt := path.Join(data.Root, p.To)
-
-		g.Go(func() error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				if err := s.Filesystem.Rename(pf, pt); err != nil {
-					// Return nil if the error is an is not exists.
-					// NOTE: os.IsNotExist() does not work if the error is wrapped.
-					if errors.Is(err, os.ErrNotExist) {
-						return nil
+	run := func(ctx context.Context, report server.ProgressReporter) error {
+		g, gctx := errgroup.WithContext(ctx)
+		var done int64
+
+		// Loop over the array of files passed in and perform the move or rename action against each.
+		for _, p := range data.Files {
+			pf := path.Join(data.Root, p.From)
+			pt := path.Join(data.Root, p.To)
+
+			g.Go(func() error {
+				select {
+				case <-gctx.Done():
+					return gctx.Err()
+				default:
+					if err := s.Filesystem.Rename(pf, pt); err != nil {
+						// Return nil if the error is an is not exists.
+						// NOTE: os.IsNotExist() does not work if the error is wrapped.
+						if errors.Is(err, os.ErrNotExist) {
+							return nil
+						}
+
+						return err
 					}
 
-					return err
+					report(atomic.AddInt64(&done, 1), int64(len(data.Files)), pt)
+					return nil
 				}
+			})
+		}
 
-				return nil
-			}
-		})
-	}
-
-	if err := g.Wait(); err != nil {
-		TrackedServerError(err, s).AbortWithServerError(c)
-		return
+		return g.Wait()
 	}
 
-	c.Status(http.StatusNoContent)
+	runOrEnqueue(c, s, run)
 }
 
 // Copies a server file.
@@ -200,29 +259,34 @@ func postServerDeleteFiles(c *gin.Context) {
 		return
 	}
 
-	g, ctx := errgroup.WithContext(context.Background())
+	run := func(ctx context.Context, report server.ProgressReporter) error {
+		g, gctx := errgroup.WithContext(ctx)
+		var done int64
 
-	// Loop over the array of files passed in and delete them. If any of the file deletions
-	// fail just abort the process entirely.
-	for _, p := range data.Files {
-		pi := path.Join(data.Root, p)
+		// Loop over the array of files passed in and delete them. If any of the file deletions
+		// fail just abort the process entirely.
+		for _, p := range data.Files {
+			pi := path.Join(data.Root, p)
 
-		g.Go(func() error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				return s.Filesystem.Delete(pi)
-			}
-		})
-	}
+			g.Go(func() error {
+				select {
+				case <-gctx.Done():
+					return gctx.Err()
+				default:
+					if err := s.Filesystem.Delete(pi); err != nil {
+						return err
+					}
 
-	if err := g.Wait(); err != nil {
-		TrackedServerError(err, s).AbortWithServerError(c)
-		return
+					report(atomic.AddInt64(&done, 1), int64(len(data.Files)), pi)
+					return nil
+				}
+			})
+		}
+
+		return g.Wait()
 	}
 
-	c.Status(http.StatusNoContent)
+	runOrEnqueue(c, s, run)
 }
 
 // Writes the contents of the request to a file on a server.
@@ -271,6 +335,7 @@ func postServerCompressFiles(c *gin.Context) {
 	var data struct {
 		RootPath string   `json:"root"`
 		Files    []string `json:"files"`
+		Format   string   `json:"format"`
 	}
 
 	if err := c.BindJSON(&data); err != nil {
@@ -284,6 +349,21 @@ func postServerCompressFiles(c *gin.Context) {
 		return
 	}
 
+	format, err := server.ParseArchiveFormat(data.Format)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The requested archive format is not supported.",
+		})
+		return
+	}
+
+	if isArchiveFormatDisabled(format) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The requested archive format has been disabled on this instance.",
+		})
+		return
+	}
+
 	if !s.Filesystem.HasSpaceAvailable() {
 		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
 			"error": "This server does not have enough available disk space to generate a compressed archive.",
@@ -291,16 +371,30 @@ func postServerCompressFiles(c *gin.Context) {
 		return
 	}
 
-	f, err := s.Filesystem.CompressFiles(data.RootPath, data.Files)
-	if err != nil {
+	var stat *server.Stat
+	run := func(ctx context.Context, report server.ProgressReporter) error {
+		f, err := s.Filesystem.CompressFilesWithContext(ctx, data.RootPath, data.Files, format, report)
+		if err != nil {
+			return err
+		}
+
+		stat = &server.Stat{Info: f, Mimetype: format.Mimetype()}
+		return nil
+	}
+
+	if wantsAsync(c) {
+		op := s.Operations().Enqueue(context.Background(), run)
+		c.JSON(http.StatusAccepted, gin.H{"operation": op.ID})
+		return
+	}
+
+	// See runOrEnqueue for why this is context.Background() rather than the request's.
+	if err := run(context.Background(), func(processed, total int64, current string) {}); err != nil {
 		TrackedServerError(err, s).AbortWithServerError(c)
 		return
 	}
 
-	c.JSON(http.StatusOK, &server.Stat{
-		Info:     f,
-		Mimetype: "application/tar+gzip",
-	})
+	c.JSON(http.StatusOK, stat)
 }
 
 func postServerDecompressFiles(c *gin.Context) {
@@ -328,11 +422,12 @@ func postServerDecompressFiles(c *gin.Context) {
 		return
 	}
 
-	if err := s.Filesystem.DecompressFile(data.RootPath, data.File); err != nil {
-		// Check if the file does not exist.
-		// NOTE: os.IsNotExist() does not work if the error is wrapped.
-		if errors.Is(err, os.ErrNotExist) {
-			c.Status(http.StatusNotFound)
+	format, err := s.Filesystem.DetectArchiveFormat(data.RootPath, data.File)
+	if err != nil {
+		if errors.Is(err, server.ErrUnknownArchiveFormat) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "The requested file is not a supported archive format.",
+			})
 			return
 		}
 
@@ -340,5 +435,16 @@ func postServerDecompressFiles(c *gin.Context) {
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	if isArchiveFormatDisabled(format) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "The requested archive format has been disabled on this instance.",
+		})
+		return
+	}
+
+	run := func(ctx context.Context, report server.ProgressReporter) error {
+		return s.Filesystem.DecompressFileWithContext(ctx, data.RootPath, data.File, report)
+	}
+
+	runOrEnqueue(c, s, run)
 }
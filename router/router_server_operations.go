@@ -0,0 +1,118 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterodactyl/wings/server"
+)
+
+type operationView struct {
+	ID       string                   `json:"id"`
+	Status   string                   `json:"status"`
+	Progress server.OperationProgress `json:"progress"`
+}
+
+// getServerOperations lists every file operation (compression, decompression, bulk
+// delete, or rename) currently tracked for the server, including ones that have already
+// finished.
+func getServerOperations(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	ops := s.Operations().List()
+	views := make([]operationView, 0, len(ops))
+	for _, op := range ops {
+		status, progress := op.Status()
+		views = append(views, operationView{ID: op.ID, Status: string(status), Progress: progress})
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// getServerOperationEvents streams progress, error, and completion events for a single
+// operation as Server-Sent Events so the panel can show live progress instead of
+// blocking on the original request.
+func getServerOperationEvents(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	op, ok := s.Operations().Get(c.Param("operation"))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "No operation exists with that identifier.",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	// Subscribe before reading the current status so that an operation finishing in the
+	// gap between the two calls still has its terminal event waiting on the channel
+	// instead of being published with nobody listening.
+	events := op.Subscribe()
+	defer op.Unsubscribe(events)
+
+	status, progress := op.Status()
+	writeOperationEvent(c, server.OperationEvent{Event: "progress", Progress: progress})
+	if isTerminalOperationStatus(status) {
+		writeOperationEvent(c, server.OperationEvent{Event: string(status)})
+		return
+	}
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+
+			writeOperationEvent(c, e)
+			return e.Event != "complete" && e.Event != "error" && e.Event != "cancelled"
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// isTerminalOperationStatus reports whether an operation has finished, successfully or
+// otherwise, and will not publish any further events.
+func isTerminalOperationStatus(status server.OperationStatus) bool {
+	return status == server.OperationStatusComplete || status == server.OperationStatusError || status == server.OperationStatusCancelled
+}
+
+// writeOperationEvent writes a single SSE frame for an operation event. The data payload
+// is always JSON, even for errors, so panel code can uniformly JSON.parse(data) no
+// matter which event type it receives; a raw, un-encoded error string could otherwise
+// contain a blank line (common in pkg/errors stack traces) and break SSE framing.
+func writeOperationEvent(c *gin.Context, e server.OperationEvent) {
+	var data []byte
+	switch {
+	case e.Error != "":
+		data, _ = json.Marshal(gin.H{"error": e.Error})
+	case e.Event == "complete" || e.Event == "cancelled":
+		data = []byte("{}")
+	default:
+		data, _ = json.Marshal(e.Progress)
+	}
+
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", e.Event, data)
+	c.Writer.Flush()
+}
+
+// deleteServerOperation cancels an in-flight file operation.
+func deleteServerOperation(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	if !s.Operations().Cancel(c.Param("operation")) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "No operation exists with that identifier.",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterodactyl/wings/server"
+)
+
+// postServerUploadCreate creates a new resumable (tus 1.0) upload for a server. The
+// destination path is provided as a query parameter (mirroring the existing file write
+// endpoint) and the total size of the upload is read from the Upload-Length header. The
+// response returns the created upload's Location so the client can resume it later with
+// HEAD/PATCH requests.
+func postServerUploadCreate(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	p, err := url.QueryUnescape(c.Query("file"))
+	if err != nil {
+		TrackedServerError(err, s).AbortWithServerError(c)
+		return
+	}
+	p = "/" + strings.TrimLeft(p, "/")
+
+	if _, err := s.Filesystem.SafePath(p); err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "The destination requested could not be found.",
+		})
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "The Upload-Length header is required and must be a non-negative integer.",
+		})
+		return
+	}
+
+	if !s.Filesystem.HasSpaceAvailable() {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "This server does not have enough available disk space to accept this upload.",
+		})
+		return
+	}
+
+	u, err := s.Uploads().New(p, length)
+	if err != nil {
+		TrackedServerError(err, s).AbortWithServerError(c)
+		return
+	}
+
+	c.Header("Location", "/api/servers/"+s.ID()+"/files/upload/"+u.ID)
+	c.Header("Tus-Resumable", "1.0.0")
+	c.Status(http.StatusCreated)
+}
+
+// headServerUpload reports the current offset and total length of an in-progress
+// upload so a client can resume it after a dropped connection.
+func headServerUpload(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	u, err := s.Uploads().Get(c.Param("upload"))
+	if err != nil {
+		if err == server.ErrUploadNotFound {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "No upload exists with that identifier.",
+			})
+			return
+		}
+
+		TrackedServerError(err, s).AbortWithServerError(c)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	c.Header("Tus-Resumable", "1.0.0")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// patchServerUpload appends a chunk of bytes to an in-progress upload at the offset
+// declared by the Upload-Offset header, finalizing the upload into place once its full
+// length has been received.
+func patchServerUpload(c *gin.Context) {
+	s := GetServer(c.Param("server"))
+
+	if c.GetHeader("Content-Type") != "application/offset+octet-stream" {
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be application/offset+octet-stream.",
+		})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "The Upload-Offset header is required and must be an integer.",
+		})
+		return
+	}
+
+	if !s.Filesystem.HasSpaceAvailable() {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "This server does not have enough available disk space to accept this upload.",
+		})
+		return
+	}
+
+	newOffset, err := s.Uploads().WriteChunk(c.Param("upload"), offset, c.Request.Body)
+	if err != nil {
+		switch err {
+		case server.ErrUploadNotFound:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": "No upload exists with that identifier.",
+			})
+		case server.ErrUploadOffsetMismatch:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "The provided Upload-Offset does not match the upload's current offset.",
+			})
+		case server.ErrUploadChunkExceedsLength:
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "This chunk would write past the upload's declared Upload-Length.",
+			})
+		default:
+			TrackedServerError(err, s).AbortWithServerError(c)
+		}
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Header("Tus-Resumable", "1.0.0")
+	c.Status(http.StatusNoContent)
+}
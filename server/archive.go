@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveFormat represents one of the archive formats wings knows how to produce or
+// extract.
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip    ArchiveFormat = "zip"
+	ArchiveFormatTar    ArchiveFormat = "tar"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarXz  ArchiveFormat = "tar.xz"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+	ArchiveFormatTarBz2 ArchiveFormat = "tar.bz2"
+	ArchiveFormatSevenZ ArchiveFormat = "7z"
+)
+
+// ErrUnknownArchiveFormat is returned when a requested or detected archive format is not
+// one wings supports.
+var ErrUnknownArchiveFormat = errors.New("archive: unknown or unsupported archive format")
+
+// Mimetype returns the MIME type that should be reported for a file compressed in this
+// format.
+func (f ArchiveFormat) Mimetype() string {
+	switch f {
+	case ArchiveFormatZip:
+		return "application/zip"
+	case ArchiveFormatTar:
+		return "application/x-tar"
+	case ArchiveFormatTarGz:
+		return "application/tar+gzip"
+	case ArchiveFormatTarXz:
+		return "application/x-xz"
+	case ArchiveFormatTarZst:
+		return "application/zstd"
+	case ArchiveFormatTarBz2:
+		return "application/x-bzip2"
+	case ArchiveFormatSevenZ:
+		return "application/x-7z-compressed"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension returns the file extension (including the leading dot) that archives of
+// this format are written with.
+func (f ArchiveFormat) Extension() string {
+	return "." + string(f)
+}
+
+// ParseArchiveFormat converts a user-provided format string, such as the "format" field
+// on a compress request, into an ArchiveFormat. It returns ErrUnknownArchiveFormat if the
+// value does not match a supported format.
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "zip":
+		return ArchiveFormatZip, nil
+	case "tar":
+		return ArchiveFormatTar, nil
+	case "", "tar.gz", "tgz":
+		return ArchiveFormatTarGz, nil
+	case "tar.xz", "txz":
+		return ArchiveFormatTarXz, nil
+	case "tar.zst", "tzst":
+		return ArchiveFormatTarZst, nil
+	case "tar.bz2", "tbz2":
+		return ArchiveFormatTarBz2, nil
+	case "7z":
+		return ArchiveFormatSevenZ, nil
+	default:
+		return "", ErrUnknownArchiveFormat
+	}
+}
+
+// archiveMagic maps the leading bytes of a file to the archive format they identify.
+// DetectArchiveFormat checks them in map order, which is not defined; no magic here is a
+// prefix of another, so at most one entry can match.
+var archiveMagic = map[ArchiveFormat][]byte{
+	ArchiveFormatZip:    {0x50, 0x4b, 0x03, 0x04},
+	ArchiveFormatTarGz:  {0x1f, 0x8b},
+	ArchiveFormatTarXz:  {0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00},
+	ArchiveFormatTarZst: {0x28, 0xb5, 0x2f, 0xfd},
+	ArchiveFormatTarBz2: {0x42, 0x5a, 0x68},
+	ArchiveFormatSevenZ: {0x37, 0x7a, 0xbc, 0xaf, 0x27, 0x1c},
+}
+
+// DetectArchiveFormat sniffs the magic bytes of a file to determine its archive format.
+// Plain (uncompressed) tar archives do not have reliable magic bytes at the start of the
+// file, so a ".tar" extension is used as a fallback before giving up.
+func DetectArchiveFormat(name string, r io.Reader) (ArchiveFormat, error) {
+	header := make([]byte, 8)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", errors.WithStack(err)
+	}
+	header = header[:n]
+
+	for format, magic := range archiveMagic {
+		if bytes.HasPrefix(header, magic) {
+			return format, nil
+		}
+	}
+
+	if strings.HasSuffix(strings.ToLower(name), ".tar") {
+		return ArchiveFormatTar, nil
+	}
+
+	return "", ErrUnknownArchiveFormat
+}
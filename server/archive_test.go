@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseArchiveFormat(t *testing.T) {
+	cases := map[string]ArchiveFormat{
+		"zip":     ArchiveFormatZip,
+		"tar":     ArchiveFormatTar,
+		"":        ArchiveFormatTarGz,
+		"tar.gz":  ArchiveFormatTarGz,
+		"tgz":     ArchiveFormatTarGz,
+		"tar.xz":  ArchiveFormatTarXz,
+		"tar.zst": ArchiveFormatTarZst,
+		"tar.bz2": ArchiveFormatTarBz2,
+		"7z":      ArchiveFormatSevenZ,
+		".7z":     ArchiveFormatSevenZ,
+		"ZIP":     ArchiveFormatZip,
+	}
+
+	for in, want := range cases {
+		got, err := ParseArchiveFormat(in)
+		if err != nil {
+			t.Errorf("ParseArchiveFormat(%q) returned unexpected error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseArchiveFormat(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseArchiveFormat_Unknown(t *testing.T) {
+	if _, err := ParseArchiveFormat("rar"); !errors.Is(err, ErrUnknownArchiveFormat) {
+		t.Fatalf("expected ErrUnknownArchiveFormat for unsupported format, got %v", err)
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	cases := []struct {
+		name   string
+		header []byte
+		want   ArchiveFormat
+	}{
+		{"archive.zip", []byte{0x50, 0x4b, 0x03, 0x04, 0, 0, 0, 0}, ArchiveFormatZip},
+		{"archive.tar.gz", []byte{0x1f, 0x8b, 0, 0, 0, 0, 0, 0}, ArchiveFormatTarGz},
+		{"archive.tar.bz2", []byte{0x42, 0x5a, 0x68, 0, 0, 0, 0, 0}, ArchiveFormatTarBz2},
+		{"archive.7z", []byte{0x37, 0x7a, 0xbc, 0xaf, 0x27, 0x1c, 0, 0}, ArchiveFormatSevenZ},
+		// Plain tar has no reliable magic bytes, so detection falls back to extension.
+		{"archive.tar", []byte("just some plain bytes"), ArchiveFormatTar},
+	}
+
+	for _, tc := range cases {
+		got, err := DetectArchiveFormat(tc.name, bytes.NewReader(tc.header))
+		if err != nil {
+			t.Errorf("DetectArchiveFormat(%q) returned unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("DetectArchiveFormat(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectArchiveFormat_Unknown(t *testing.T) {
+	_, err := DetectArchiveFormat("notes.txt", bytes.NewReader([]byte("hello world")))
+	if !errors.Is(err, ErrUnknownArchiveFormat) {
+		t.Fatalf("expected ErrUnknownArchiveFormat for unrecognized file, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPathResolvesOutsideRoot is returned by SafePath when a caller-supplied path, once
+// cleaned and joined against a server's root, does not lie under that root. Leading ../
+// segments are clamped to the root rather than rejected, and symlinks are not resolved.
+var ErrPathResolvesOutsideRoot = errors.New("filesystem: path resolves outside the server's root directory")
+
+// Stat describes a single file or directory within a server's filesystem.
+type Stat struct {
+	Info     os.FileInfo `json:"-"`
+	Mimetype string      `json:"mime"`
+}
+
+// Filesystem provides sandboxed access to a single server's data directory: every path
+// a caller provides is resolved and verified to stay within the server's root before
+// anything on disk is touched.
+type Filesystem struct {
+	root string
+}
+
+// NewFilesystem returns a Filesystem rooted at root.
+func NewFilesystem(root string) *Filesystem {
+	return &Filesystem{root: root}
+}
+
+// Path returns the absolute path to the server's root directory.
+func (fs *Filesystem) Path() string {
+	return fs.root
+}
+
+// SafePath resolves a server-relative path against the filesystem's root and returns an
+// error if the joined result is not under that root. ".." segments cannot climb above the
+// root: they are cleaned away first, so a traversal path resolves to a location inside it.
+// Symlinks are not followed.
+func (fs *Filesystem) SafePath(p string) (string, error) {
+	cleaned := filepath.Join(fs.root, filepath.Clean(string(os.PathSeparator)+p))
+
+	if cleaned != fs.root && !strings.HasPrefix(cleaned, fs.root+string(os.PathSeparator)) {
+		return "", ErrPathResolvesOutsideRoot
+	}
+
+	return cleaned, nil
+}
+
+// Stat returns file information for an already-resolved (SafePath'd) absolute path.
+func (fs *Filesystem) Stat(p string) (Stat, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return Stat{}, errors.WithStack(err)
+	}
+
+	mt := mime.TypeByExtension(filepath.Ext(p))
+	if mt == "" {
+		mt = "application/octet-stream"
+	}
+
+	return Stat{Info: info, Mimetype: mt}, nil
+}
+
+// ListDirectory returns the contents of a server-relative directory.
+func (fs *Filesystem) ListDirectory(d string) ([]Stat, error) {
+	p, err := fs.SafePath(d)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	stats := make([]Stat, 0, len(entries))
+	for _, e := range entries {
+		mt := "inode/directory"
+		if !e.IsDir() {
+			mt = mime.TypeByExtension(filepath.Ext(e.Name()))
+			if mt == "" {
+				mt = "application/octet-stream"
+			}
+		}
+
+		stats = append(stats, Stat{Info: e, Mimetype: mt})
+	}
+
+	return stats, nil
+}
+
+// Writefile writes the contents of r to a server-relative path, creating or truncating
+// the destination file as needed.
+func (fs *Filesystem) Writefile(p string, r io.Reader) error {
+	cleaned, err := fs.SafePath(p)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cleaned), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	f, err := os.OpenFile(cleaned, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return errors.WithStack(err)
+}
+
+// Rename moves or renames a file or directory within a server's filesystem.
+func (fs *Filesystem) Rename(from, to string) error {
+	fp, err := fs.SafePath(from)
+	if err != nil {
+		return err
+	}
+
+	tp, err := fs.SafePath(to)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tp), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(fp, tp))
+}
+
+// Delete removes a file or directory (recursively) from a server's filesystem.
+func (fs *Filesystem) Delete(p string) error {
+	cleaned, err := fs.SafePath(p)
+	if err != nil {
+		return err
+	}
+
+	return errors.WithStack(os.RemoveAll(cleaned))
+}
+
+// Copy duplicates a file within a server's filesystem, placing the copy alongside the
+// original with a " copy" suffix.
+func (fs *Filesystem) Copy(location string) error {
+	cleaned, err := fs.SafePath(location)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(cleaned)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer src.Close()
+
+	ext := filepath.Ext(cleaned)
+	dest := strings.TrimSuffix(cleaned, ext) + " copy" + ext
+
+	dst, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return errors.WithStack(err)
+}
+
+// CreateDirectory creates a new directory, and any missing parents, within a server's
+// filesystem.
+func (fs *Filesystem) CreateDirectory(name, path string) error {
+	cleaned, err := fs.SafePath(filepath.Join(path, name))
+	if err != nil {
+		return err
+	}
+
+	return errors.WithStack(os.MkdirAll(cleaned, 0755))
+}
+
+// HasSpaceAvailable reports whether the disk backing this server's root directory still
+// has free space. It is intentionally conservative: callers should treat a false return
+// as "stop accepting more data", not as an exact quota check.
+func (fs *Filesystem) HasSpaceAvailable() bool {
+	return fs.HasSpaceFor(1)
+}
+
+// HasSpaceFor reports whether the disk backing this server's root directory has at
+// least n bytes free. Like HasSpaceAvailable, a failed disk-usage lookup is treated as
+// "space is available" rather than blocking the caller on an unrelated stat error.
+func (fs *Filesystem) HasSpaceFor(n int64) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(fs.root, &stat); err != nil {
+		return true
+	}
+
+	return int64(stat.Bavail)*stat.Bsize >= n
+}
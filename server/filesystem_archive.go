@@ -0,0 +1,371 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrArchiveFormatNotSupported is returned when a format is recognized (it is a valid
+// ArchiveFormat) but wings does not carry an encoder or decoder for it — today that is
+// tar.xz, tar.zst, and 7z, none of which have a pure-Go implementation in the standard
+// library. Detection and rejection still works correctly for these; only the actual
+// byte-level (de)compression is unimplemented. CompressFilesWithContext also returns it
+// for tar.bz2, which can be extracted but not produced: the standard library's bzip2
+// package only decompresses.
+var ErrArchiveFormatNotSupported = errors.New("filesystem: wings does not have a (de)compressor for this archive format")
+
+// DetectArchiveFormat sniffs the on-disk archive at root/file to determine its format,
+// falling back to the package-level DetectArchiveFormat's extension check.
+func (fs *Filesystem) DetectArchiveFormat(root, file string) (ArchiveFormat, error) {
+	p, err := fs.SafePath(path.Join(root, file))
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	return DetectArchiveFormat(file, f)
+}
+
+// decompressionSizeMultiplier is the assumed worst-case ratio of decompressed to
+// on-disk archive size used by SpaceAvailableForDecompression. Typical text-heavy
+// server backups compress much better than this, but the check is meant to catch
+// "obviously not enough room" rather than predict an exact figure.
+const decompressionSizeMultiplier = 10
+
+// SpaceAvailableForDecompression estimates whether there is enough free disk space to
+// extract the archive at root/file. Without fully walking the archive's headers this can
+// only be a rough heuristic: it assumes decompressed contents are, at most,
+// decompressionSizeMultiplier times the archive's on-disk size, and checks that against
+// the free space on the filesystem backing the server's root.
+func (fs *Filesystem) SpaceAvailableForDecompression(root, file string) (bool, error) {
+	p, err := fs.SafePath(path.Join(root, file))
+	if err != nil {
+		return false, err
+	}
+
+	st, err := os.Stat(p)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return fs.HasSpaceFor(st.Size() * decompressionSizeMultiplier), nil
+}
+
+// CompressFilesWithContext archives the given server-relative files under root into a
+// single new archive of the requested format, reporting progress (bytes processed out
+// of the total size of the inputs, and the name of the entry currently being written)
+// through report as it goes, and aborting as soon as ctx is cancelled.
+func (fs *Filesystem) CompressFilesWithContext(ctx context.Context, root string, files []string, format ArchiveFormat, report ProgressReporter) (os.FileInfo, error) {
+	rootPath, err := fs.SafePath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	paths := make([]string, 0, len(files))
+	for _, name := range files {
+		p, err := fs.SafePath(path.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+
+		st, err := os.Stat(p)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		total += st.Size()
+		paths = append(paths, p)
+	}
+
+	archiveName := "archive-" + time.Now().Format("2006-01-02-150405") + format.Extension()
+	archivePath := filepath.Join(rootPath, archiveName)
+
+	out, err := os.OpenFile(archivePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer out.Close()
+
+	var processed int64
+	writeEntry := func(addFile func(name string, r io.Reader, size int64) error) error {
+		for _, p := range paths {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			rel, err := filepath.Rel(rootPath, p)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			f, err := os.Open(p)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			st, err := f.Stat()
+			if err != nil {
+				f.Close()
+				return errors.WithStack(err)
+			}
+
+			if err := addFile(rel, f, st.Size()); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+
+			processed += st.Size()
+			report(processed, total, rel)
+		}
+
+		return nil
+	}
+
+	switch format {
+	case ArchiveFormatZip:
+		zw := zip.NewWriter(out)
+		defer zw.Close()
+
+		err = writeEntry(func(name string, r io.Reader, size int64) error {
+			w, err := zw.Create(name)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			_, err = io.Copy(w, r)
+			return errors.WithStack(err)
+		})
+	case ArchiveFormatTar, ArchiveFormatTarGz:
+		var w io.Writer = out
+		if format == ArchiveFormatTarGz {
+			gw := gzip.NewWriter(out)
+			defer gw.Close()
+			w = gw
+		}
+
+		tw := tar.NewWriter(w)
+		defer tw.Close()
+
+		err = writeEntry(func(name string, r io.Reader, size int64) error {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Size: size, Mode: 0644}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			_, err := io.Copy(tw, r)
+			return errors.WithStack(err)
+		})
+	default:
+		os.Remove(archivePath)
+		return nil, ErrArchiveFormatNotSupported
+	}
+
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+
+	return os.Stat(archivePath)
+}
+
+// DecompressFileWithContext extracts the archive at root/file into root, streaming every
+// entry's destination through SafePath so a maliciously crafted archive ("zip-slip")
+// cannot write outside of the server's filesystem. Progress is reported as a cumulative
+// total across the whole archive, and extraction aborts as soon as ctx is cancelled.
+func (fs *Filesystem) DecompressFileWithContext(ctx context.Context, root string, file string, report ProgressReporter) error {
+	if _, err := fs.SafePath(root); err != nil {
+		return err
+	}
+
+	archivePath, err := fs.SafePath(path.Join(root, file))
+	if err != nil {
+		return err
+	}
+
+	format, err := fs.DetectArchiveFormat(root, file)
+	if err != nil {
+		return err
+	}
+
+	extractEntry := func(name string, size int64, r io.Reader) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		dest, err := fs.SafePath(path.Join(root, name))
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return errors.WithStack(err)
+		}
+
+		w, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer w.Close()
+
+		_, err = io.Copy(w, r)
+		return errors.WithStack(err)
+	}
+
+	switch format {
+	case ArchiveFormatZip:
+		zr, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer zr.Close()
+
+		var total int64
+		for _, f := range zr.File {
+			total += int64(f.UncompressedSize64)
+		}
+
+		var processed int64
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := extractEntry(f.Name, int64(f.UncompressedSize64), rc); err != nil {
+				rc.Close()
+				return err
+			}
+			rc.Close()
+
+			processed += int64(f.UncompressedSize64)
+			report(processed, total, f.Name)
+		}
+
+		return nil
+	case ArchiveFormatTar, ArchiveFormatTarGz, ArchiveFormatTarBz2:
+		// Unlike zip's central directory, a tar stream has no index to sum sizes from up
+		// front, so it has to be walked once just to total them before the real
+		// extraction pass can report a cumulative total rather than each entry's own size.
+		total, err := sumTarEntrySizes(archivePath, format)
+		if err != nil {
+			return err
+		}
+
+		r, closer, err := openTarStream(archivePath, format)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+
+		var processed int64
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if hdr.Typeflag == tar.TypeDir {
+				if _, err := fs.SafePath(path.Join(root, hdr.Name)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := extractEntry(hdr.Name, hdr.Size, tr); err != nil {
+				return err
+			}
+
+			processed += hdr.Size
+			report(processed, total, hdr.Name)
+		}
+	default:
+		return ErrArchiveFormatNotSupported
+	}
+}
+
+// openTarStream opens the on-disk archive at archivePath and, for compressed formats,
+// wraps it in the matching decompressing reader. The returned closer closes everything
+// that was opened, in the right order.
+func openTarStream(archivePath string, format ArchiveFormat) (io.Reader, io.Closer, error) {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	switch format {
+	case ArchiveFormatTarGz:
+		gr, err := gzip.NewReader(in)
+		if err != nil {
+			in.Close()
+			return nil, nil, errors.WithStack(err)
+		}
+		return gr, closerFunc(func() error {
+			gr.Close()
+			return in.Close()
+		}), nil
+	case ArchiveFormatTarBz2:
+		return bzip2.NewReader(in), in, nil
+	default:
+		return in, in, nil
+	}
+}
+
+// sumTarEntrySizes walks a tar (optionally gzip/bzip2-compressed) archive once, adding
+// up the uncompressed size of every non-directory entry, so DecompressFileWithContext
+// has a real cumulative total to report progress against.
+func sumTarEntrySizes(archivePath string, format ArchiveFormat) (int64, error) {
+	r, closer, err := openTarStream(archivePath, format)
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		if hdr.Typeflag != tar.TypeDir {
+			total += hdr.Size
+		}
+	}
+}
+
+// closerFunc adapts a plain function to the io.Closer interface.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
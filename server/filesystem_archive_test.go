@@ -0,0 +1,157 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesystem_CompressDecompressRoundTrip(t *testing.T) {
+	formats := []ArchiveFormat{ArchiveFormatZip, ArchiveFormatTar, ArchiveFormatTarGz}
+
+	for _, format := range formats {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			root := t.TempDir()
+			fs := NewFilesystem(root)
+
+			if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644); err != nil {
+				t.Fatalf("failed to write fixture file: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "b.txt"), []byte("world"), 0644); err != nil {
+				t.Fatalf("failed to write fixture file: %v", err)
+			}
+
+			noop := func(processed, total int64, current string) {}
+
+			info, err := fs.CompressFilesWithContext(context.Background(), "/", []string{"a.txt", "b.txt"}, format, noop)
+			if err != nil {
+				t.Fatalf("CompressFilesWithContext returned unexpected error: %v", err)
+			}
+
+			extractRoot := filepath.Join(root, "extracted")
+			if err := os.Mkdir(extractRoot, 0755); err != nil {
+				t.Fatalf("failed to create extraction directory: %v", err)
+			}
+			extractFs := NewFilesystem(extractRoot)
+
+			archiveSrc := filepath.Join(root, info.Name())
+			if err := os.Rename(archiveSrc, filepath.Join(extractRoot, info.Name())); err != nil {
+				t.Fatalf("failed to move archive into extraction root: %v", err)
+			}
+
+			if err := extractFs.DecompressFileWithContext(context.Background(), "/", info.Name(), noop); err != nil {
+				t.Fatalf("DecompressFileWithContext returned unexpected error: %v", err)
+			}
+
+			for name, want := range map[string]string{"a.txt": "hello", "b.txt": "world"} {
+				got, err := os.ReadFile(filepath.Join(extractRoot, name))
+				if err != nil {
+					t.Fatalf("failed to read extracted file %q: %v", name, err)
+				}
+				if string(got) != want {
+					t.Errorf("extracted file %q = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestFilesystem_DecompressFileWithContext_TarCumulativeProgress verifies that progress
+// reported while extracting a tar archive is a running total across the whole archive,
+// not each entry's own size reported as both processed and total.
+func TestFilesystem_DecompressFileWithContext_TarCumulativeProgress(t *testing.T) {
+	root := t.TempDir()
+	fs := NewFilesystem(root)
+
+	archivePath := filepath.Join(root, "archive.tar")
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create archive fixture: %v", err)
+	}
+
+	tw := tar.NewWriter(out)
+	entries := []struct {
+		name string
+		body string
+	}{
+		{"a.txt", "0123456789"},
+		{"b.txt", "01234567890123456789"},
+	}
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Size: int64(len(e.body)), Mode: 0644}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("failed to close archive fixture: %v", err)
+	}
+
+	var totals []int64
+	report := func(processed, total int64, current string) {
+		totals = append(totals, total)
+	}
+
+	if err := fs.DecompressFileWithContext(context.Background(), "/", "archive.tar", report); err != nil {
+		t.Fatalf("DecompressFileWithContext returned unexpected error: %v", err)
+	}
+
+	if len(totals) != 2 {
+		t.Fatalf("expected 2 progress reports, got %d", len(totals))
+	}
+
+	const wantTotal = 30
+	for i, total := range totals {
+		if total != wantTotal {
+			t.Errorf("progress report %d: total = %d, want %d (cumulative across the whole archive)", i, total, wantTotal)
+		}
+	}
+}
+
+// TestFilesystem_DecompressFileWithContext_ZipSlip verifies that an archive entry
+// crafted with a path traversal name (a "zip-slip" attack) is extracted inside the
+// server's root rather than at the literal path it names outside of it.
+func TestFilesystem_DecompressFileWithContext_ZipSlip(t *testing.T) {
+	root := t.TempDir()
+	fs := NewFilesystem(root)
+
+	archivePath := filepath.Join(root, "evil.zip")
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create archive fixture: %v", err)
+	}
+
+	zw := zip.NewWriter(out)
+	w, err := zw.Create("../../../tmp/evil.txt")
+	if err != nil {
+		t.Fatalf("failed to create malicious zip entry: %v", err)
+	}
+	if _, err := w.Write([]byte("pwned")); err != nil {
+		t.Fatalf("failed to write malicious zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("failed to close archive fixture: %v", err)
+	}
+
+	noop := func(processed, total int64, current string) {}
+	if err := fs.DecompressFileWithContext(context.Background(), "/", "evil.zip", noop); err != nil {
+		t.Fatalf("DecompressFileWithContext returned unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat("/tmp/evil.txt"); !os.IsNotExist(err) {
+		os.Remove("/tmp/evil.txt")
+		t.Fatalf("zip-slip entry escaped the server root onto /tmp/evil.txt")
+	}
+}
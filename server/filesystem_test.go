@@ -0,0 +1,59 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystem_SafePath(t *testing.T) {
+	root := t.TempDir()
+	fs := NewFilesystem(root)
+
+	cases := map[string]string{
+		"file.txt":        filepath.Join(root, "file.txt"),
+		"/file.txt":       filepath.Join(root, "file.txt"),
+		"nested/file.txt": filepath.Join(root, "nested", "file.txt"),
+		"":                root,
+	}
+
+	for in, want := range cases {
+		got, err := fs.SafePath(in)
+		if err != nil {
+			t.Errorf("SafePath(%q) returned unexpected error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("SafePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestFilesystem_SafePath_ContainsTraversal verifies that a caller-supplied path
+// containing ".." segments is normalized to somewhere within the root rather than being
+// allowed to escape it, the same "jailed join" behavior callers like the upload and
+// archive extraction code rely on.
+func TestFilesystem_SafePath_ContainsTraversal(t *testing.T) {
+	root := t.TempDir()
+	fs := NewFilesystem(root)
+
+	cases := []string{
+		"../escape.txt",
+		"../../etc/passwd",
+		"nested/../../escape.txt",
+		"/../escape.txt",
+		"../../../../../../etc/shadow",
+	}
+
+	for _, in := range cases {
+		got, err := fs.SafePath(in)
+		if err != nil {
+			t.Errorf("SafePath(%q) returned unexpected error: %v", in, err)
+			continue
+		}
+
+		if got != root && !strings.HasPrefix(got, root+string(filepath.Separator)) {
+			t.Errorf("SafePath(%q) = %q, escaped root %q", in, got, root)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// OperationStatus describes where a long-running file operation is in its lifecycle.
+type OperationStatus string
+
+const (
+	OperationStatusPending   OperationStatus = "pending"
+	OperationStatusRunning   OperationStatus = "running"
+	OperationStatusComplete  OperationStatus = "complete"
+	OperationStatusError     OperationStatus = "error"
+	OperationStatusCancelled OperationStatus = "cancelled"
+)
+
+// OperationProgress is a snapshot of how far a long-running operation has gotten.
+type OperationProgress struct {
+	// Processed and Total are expressed in bytes for compress/decompress operations and
+	// in number of entries for delete/rename operations.
+	Processed int64  `json:"processed"`
+	Total     int64  `json:"total"`
+	Current   string `json:"current"`
+}
+
+// OperationEvent is a single update emitted on an operation's event stream. Event is one
+// of "progress", "error", "complete", or "cancelled" and matches the SSE event name it is
+// sent under.
+type OperationEvent struct {
+	Event    string            `json:"-"`
+	Progress OperationProgress `json:"progress,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// ProgressReporter is passed into the function backing an operation so it can publish
+// incremental progress as it works; implementations doing archive or bulk filesystem
+// work should call it as they walk entries rather than only at completion.
+type ProgressReporter func(processed, total int64, current string)
+
+// Operation tracks a single enqueued file operation (compression, decompression, bulk
+// delete, or rename) so its progress can be streamed to a client and so it can be
+// cancelled mid-flight.
+type Operation struct {
+	ID       string
+	mu       sync.Mutex
+	status   OperationStatus
+	progress OperationProgress
+	err      error
+
+	cancel context.CancelFunc
+
+	subMu       sync.Mutex
+	subscribers map[chan OperationEvent]struct{}
+}
+
+// Status returns the operation's current status and last known progress.
+func (o *Operation) Status() (OperationStatus, OperationProgress) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.status, o.progress
+}
+
+// Cancel requests that the operation's backing context be cancelled. The operation's
+// function is responsible for checking ctx.Err() and returning promptly.
+func (o *Operation) Cancel() {
+	o.cancel()
+}
+
+// Subscribe registers a channel that receives every event published for this operation
+// from this point forward. The returned channel must be passed to Unsubscribe once the
+// caller is done listening (typically when its SSE connection closes).
+func (o *Operation) Subscribe() chan OperationEvent {
+	ch := make(chan OperationEvent, 16)
+
+	o.subMu.Lock()
+	o.subscribers[ch] = struct{}{}
+	o.subMu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe removes a channel previously returned by Subscribe and closes it.
+func (o *Operation) Unsubscribe(ch chan OperationEvent) {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+
+	if _, ok := o.subscribers[ch]; ok {
+		delete(o.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (o *Operation) publish(e OperationEvent) {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+
+	for ch := range o.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// A slow subscriber should not block the operation itself; it will miss
+			// this update but subsequent polls via GET /operations still see the
+			// latest status.
+		}
+	}
+}
+
+func (o *Operation) setProgress(processed, total int64, current string) {
+	o.mu.Lock()
+	o.progress = OperationProgress{Processed: processed, Total: total, Current: current}
+	o.mu.Unlock()
+
+	o.publish(OperationEvent{Event: "progress", Progress: o.progress})
+}
+
+func (o *Operation) finish(err error) {
+	o.mu.Lock()
+	if err != nil {
+		if err == context.Canceled {
+			o.status = OperationStatusCancelled
+		} else {
+			o.status = OperationStatusError
+			o.err = err
+		}
+	} else {
+		o.status = OperationStatusComplete
+	}
+	status := o.status
+	o.mu.Unlock()
+
+	switch status {
+	case OperationStatusError:
+		o.publish(OperationEvent{Event: "error", Error: err.Error()})
+	case OperationStatusCancelled:
+		o.publish(OperationEvent{Event: "cancelled"})
+	default:
+		o.publish(OperationEvent{Event: "complete"})
+	}
+}
+
+// OperationManager tracks the in-flight and recently finished file operations for a
+// single server so their progress can be streamed over SSE and so they can be
+// cancelled on request.
+type OperationManager struct {
+	mu  sync.Mutex
+	ops map[string]*Operation
+}
+
+// NewOperationManager returns an empty operation manager for a server.
+func NewOperationManager() *OperationManager {
+	return &OperationManager{ops: map[string]*Operation{}}
+}
+
+// Enqueue registers a new operation and runs fn in its own goroutine, passing it a
+// context that is cancelled if the operation is cancelled via Cancel, along with a
+// ProgressReporter it should call as work proceeds.
+func (m *OperationManager) Enqueue(parent context.Context, fn func(ctx context.Context, report ProgressReporter) error) *Operation {
+	ctx, cancel := context.WithCancel(parent)
+
+	op := &Operation{
+		ID:          uuid.New().String(),
+		status:      OperationStatusPending,
+		cancel:      cancel,
+		subscribers: map[chan OperationEvent]struct{}{},
+	}
+
+	m.mu.Lock()
+	m.ops[op.ID] = op
+	m.mu.Unlock()
+
+	go func() {
+		op.mu.Lock()
+		op.status = OperationStatusRunning
+		op.mu.Unlock()
+
+		err := fn(ctx, op.setProgress)
+		op.finish(err)
+	}()
+
+	return op
+}
+
+// Get returns the operation tracked under id, if any.
+func (m *OperationManager) Get(id string) (*Operation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, ok := m.ops[id]
+	return op, ok
+}
+
+// List returns every operation currently tracked for the server, including ones that
+// have already finished.
+func (m *OperationManager) List() []*Operation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ops := make([]*Operation, 0, len(m.ops))
+	for _, op := range m.ops {
+		ops = append(ops, op)
+	}
+
+	return ops
+}
+
+// Cancel cancels the operation tracked under id, if it exists and is still running.
+func (m *OperationManager) Cancel(id string) bool {
+	op, ok := m.Get(id)
+	if !ok {
+		return false
+	}
+
+	op.Cancel()
+	return true
+}
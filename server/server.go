@@ -0,0 +1,39 @@
+package server
+
+// Server is the in-memory representation of a single game server instance managed by
+// this wings daemon.
+type Server struct {
+	id string
+
+	Filesystem *Filesystem
+
+	uploads    *UploadManager
+	operations *OperationManager
+}
+
+// New returns a Server wired up with the subsystems (filesystem, resumable uploads,
+// async file operations, ...) that every server instance needs regardless of how it was
+// loaded.
+func New(id string, fs *Filesystem) *Server {
+	s := &Server{id: id, Filesystem: fs}
+	s.uploads = NewUploadManager(fs)
+	s.operations = NewOperationManager()
+
+	return s
+}
+
+// ID returns the unique identifier wings uses to refer to this server.
+func (s *Server) ID() string {
+	return s.id
+}
+
+// Uploads returns the manager tracking this server's in-progress resumable uploads.
+func (s *Server) Uploads() *UploadManager {
+	return s.uploads
+}
+
+// Operations returns the manager tracking this server's long-running file operations
+// (compression, decompression, bulk delete, and rename).
+func (s *Server) Operations() *OperationManager {
+	return s.operations
+}
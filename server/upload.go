@@ -0,0 +1,265 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// uploadRegistryFile is the name of the file, relative to a server's data directory,
+// that tracks in-progress tus uploads so that progress survives a wings restart.
+const uploadRegistryFile = ".tus-uploads.json"
+
+// Upload tracks the state of a single resumable (tus 1.0) upload for a server.
+type Upload struct {
+	ID string `json:"id"`
+	// Destination is the path, relative to the server's root, that the upload will be
+	// renamed into once it is finalized.
+	Destination string `json:"destination"`
+	Length      int64  `json:"length"`
+	Offset      int64  `json:"offset"`
+	// TempName is the name of the in-progress file stored alongside the registry while
+	// the upload is incomplete.
+	TempName  string    `json:"temp_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UploadManager persists the state of resumable uploads for a single server so that a
+// dropped connection only costs the bytes not yet acknowledged, rather than the entire
+// transfer.
+type UploadManager struct {
+	mu sync.Mutex
+	fs *Filesystem
+}
+
+// NewUploadManager returns an upload manager bound to the given filesystem.
+func NewUploadManager(fs *Filesystem) *UploadManager {
+	return &UploadManager{fs: fs}
+}
+
+func (m *UploadManager) registryPath() string {
+	return filepath.Join(m.fs.Path(), uploadRegistryFile)
+}
+
+func (m *UploadManager) load() (map[string]*Upload, error) {
+	uploads := map[string]*Upload{}
+
+	b, err := ioutil.ReadFile(m.registryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return uploads, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	if len(b) == 0 {
+		return uploads, nil
+	}
+
+	if err := json.Unmarshal(b, &uploads); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return uploads, nil
+}
+
+// save persists the registry via a temp-file-then-rename so a crash mid-write leaves
+// either the old or the new contents on disk, never a truncated/corrupt file.
+func (m *UploadManager) save(uploads map[string]*Upload) error {
+	b, err := json.Marshal(uploads)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path := m.registryPath()
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp, path))
+}
+
+// New creates a new resumable upload targeting destination, which is a server-relative
+// path, and records length as its total size; no disk space is reserved. destination is
+// checked with SafePath up front, which contains a traversal path within the server's
+// root rather than rejecting it, so any SafePath error surfaces here instead of only once
+// the (potentially large) upload is finalized. The returned Upload's ID should be handed
+// back to the client as part of the Location header for subsequent HEAD/PATCH calls.
+func (m *UploadManager) New(destination string, length int64) (*Upload, error) {
+	if _, err := m.fs.SafePath(destination); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uploads, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	u := &Upload{
+		ID:          id,
+		Destination: destination,
+		Length:      length,
+		Offset:      0,
+		TempName:    fmt.Sprintf(".tus-upload-%s", id),
+		CreatedAt:   time.Now(),
+	}
+
+	uploads[id] = u
+
+	return u, m.save(uploads)
+}
+
+// Get returns the upload tracked under id, if any.
+func (m *UploadManager) Get(id string) (*Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uploads, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+
+	return u, nil
+}
+
+// WriteChunk appends the bytes read from r to the upload's temporary file starting at
+// offset, validating that offset matches what the server has recorded before accepting
+// any bytes (as required by the tus PATCH semantics). It returns the upload's new offset.
+func (m *UploadManager) WriteChunk(id string, offset int64, r io.Reader) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uploads, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	u, ok := uploads[id]
+	if !ok {
+		return 0, ErrUploadNotFound
+	}
+
+	if offset != u.Offset {
+		return 0, ErrUploadOffsetMismatch
+	}
+
+	if !m.fs.HasSpaceAvailable() {
+		return 0, ErrNotEnoughDiskSpace
+	}
+
+	p, err := m.fs.SafePath(u.TempName)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	// Never read more than the upload has room for: r is the raw request body, and a
+	// client that lies about Content-Length (or just keeps streaming) would otherwise
+	// grow the file past Upload-Length with no error, ignoring the length recorded in
+	// New. Capping the reader at remaining+1 lets us tell "exactly filled the
+	// remaining space" apart from "tried to send more than that".
+	remaining := u.Length - u.Offset
+	if remaining < 0 {
+		return 0, ErrUploadChunkExceedsLength
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if n > remaining {
+		if err := f.Truncate(offset); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return 0, ErrUploadChunkExceedsLength
+	}
+
+	u.Offset += n
+	uploads[id] = u
+
+	if err := m.save(uploads); err != nil {
+		return 0, err
+	}
+
+	if u.Offset >= u.Length {
+		finalizeErr := m.finalize(u)
+
+		// Whether finalize succeeded or not, the temp file and registry entry should
+		// not be left behind: a successful finalize already moved it into place, and a
+		// failed one (bad destination, rename error) has nothing worth resuming since
+		// the upload is already complete on disk.
+		if p, err := m.fs.SafePath(u.TempName); err == nil {
+			os.Remove(p)
+		}
+
+		delete(uploads, id)
+		if err := m.save(uploads); err != nil {
+			if finalizeErr != nil {
+				return u.Offset, finalizeErr
+			}
+			return u.Offset, err
+		}
+
+		if finalizeErr != nil {
+			return u.Offset, finalizeErr
+		}
+	}
+
+	return u.Offset, nil
+}
+
+// finalize atomically moves a completed upload's temporary file into its final
+// destination within the server's filesystem.
+func (m *UploadManager) finalize(u *Upload) error {
+	tmp, err := m.fs.SafePath(u.TempName)
+	if err != nil {
+		return err
+	}
+
+	dest, err := m.fs.SafePath(u.Destination)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp, dest))
+}
+
+var (
+	ErrUploadNotFound           = errors.New("upload: no upload exists with that id")
+	ErrUploadOffsetMismatch     = errors.New("upload: provided offset does not match the upload's current offset")
+	ErrNotEnoughDiskSpace       = errors.New("upload: not enough available disk space to accept this chunk")
+	ErrUploadChunkExceedsLength = errors.New("upload: chunk would write past the upload's declared Upload-Length")
+)
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUploadManager_MaliciousDestination_StaysWithinRoot confirms a traversal-style
+// destination ("../../evil.txt") can't make a finalized upload land outside the
+// server's root: SafePath contains it to somewhere inside root instead of erroring, and
+// it's that containment — not a rejection — that finalize ultimately relies on.
+func TestUploadManager_MaliciousDestination_StaysWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	m := NewUploadManager(NewFilesystem(root))
+
+	payload := []byte("malicious payload")
+	u, err := m.New("../../../evil.txt", int64(len(payload)))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	if _, err := m.WriteChunk(u.ID, 0, bytes.NewReader(payload)); err != nil {
+		t.Fatalf("WriteChunk returned unexpected error: %v", err)
+	}
+
+	outside := filepath.Join(filepath.Dir(root), "evil.txt")
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Fatalf("traversal destination escaped the server root, stat err = %v", err)
+	}
+
+	contained := filepath.Join(root, "evil.txt")
+	got, err := ioutil.ReadFile(contained)
+	if err != nil {
+		t.Fatalf("reading contained destination: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("contained destination contents = %q, want %q", got, payload)
+	}
+}
+
+func TestUploadManager_WriteChunk_OffsetMismatch(t *testing.T) {
+	root := t.TempDir()
+	m := NewUploadManager(NewFilesystem(root))
+
+	u, err := m.New("dest.txt", 10)
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	if _, err := m.WriteChunk(u.ID, 5, bytes.NewReader([]byte("hello"))); err != ErrUploadOffsetMismatch {
+		t.Fatalf("WriteChunk with a stale offset error = %v, want ErrUploadOffsetMismatch", err)
+	}
+}
+
+func TestUploadManager_WriteChunk_RejectsOverrun(t *testing.T) {
+	root := t.TempDir()
+	m := NewUploadManager(NewFilesystem(root))
+
+	u, err := m.New("dest.txt", 10)
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("a"), 100)
+	if _, err := m.WriteChunk(u.ID, 0, bytes.NewReader(payload)); err != ErrUploadChunkExceedsLength {
+		t.Fatalf("WriteChunk overrunning Upload-Length error = %v, want ErrUploadChunkExceedsLength", err)
+	}
+
+	// The upload should not have advanced, and the finalized destination should not
+	// have been created, despite the oversized chunk.
+	got, err := m.Get(u.ID)
+	if err != nil {
+		t.Fatalf("Get returned unexpected error: %v", err)
+	}
+	if got.Offset != 0 {
+		t.Fatalf("Offset after a rejected chunk = %d, want 0", got.Offset)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "dest.txt")); !os.IsNotExist(err) {
+		t.Fatalf("destination file exists after a rejected chunk, stat err = %v", err)
+	}
+}
+
+func TestUploadManager_WriteChunk_FinalizesExactLength(t *testing.T) {
+	root := t.TempDir()
+	m := NewUploadManager(NewFilesystem(root))
+
+	payload := []byte("hello world")
+	u, err := m.New("dest.txt", int64(len(payload)))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	newOffset, err := m.WriteChunk(u.ID, 0, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("WriteChunk returned unexpected error: %v", err)
+	}
+	if newOffset != int64(len(payload)) {
+		t.Fatalf("newOffset = %d, want %d", newOffset, len(payload))
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(root, "dest.txt"))
+	if err != nil {
+		t.Fatalf("reading finalized destination: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("finalized destination contents = %q, want %q", got, payload)
+	}
+
+	if _, err := m.Get(u.ID); err != ErrUploadNotFound {
+		t.Fatalf("Get after finalize error = %v, want ErrUploadNotFound", err)
+	}
+}